@@ -0,0 +1,157 @@
+// Safe in-place updates to playlists.xml: refuse to run while Rhythmbox is
+// live, write atomically, and keep rotating backups.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RhythmboxRunning reports whether Rhythmbox appears to be running, via
+// either a live "rhythmbox" process or its name being owned on the session
+// D-Bus bus. A false negative is possible (e.g. no D-Bus session, no
+// /proc); this is a best-effort safety check, not a guarantee.
+func RhythmboxRunning() bool {
+	if rhythmboxProcessRunning() {
+		return true
+	}
+	return rhythmboxOwnsSessionBusName()
+}
+
+func rhythmboxProcessRunning() bool {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return false
+	}
+	for _, e := range entries {
+		if _, err := strconv.Atoi(e.Name()); err != nil {
+			continue // not a pid directory
+		}
+		comm, err := os.ReadFile(filepath.Join("/proc", e.Name(), "comm"))
+		if err != nil {
+			continue // process may have exited; not a failure worth reporting
+		}
+		if strings.TrimSpace(string(comm)) == "rhythmbox" {
+			return true
+		}
+	}
+	return false
+}
+
+func rhythmboxOwnsSessionBusName() bool {
+	out, err := exec.Command("dbus-send", "--session", "--dest=org.freedesktop.DBus",
+		"--type=method_call", "--print-reply",
+		"/org/freedesktop/DBus", "org.freedesktop.DBus.NameHasOwner",
+		"string:org.gnome.Rhythmbox3").Output()
+	if err != nil {
+		return false // dbus-send unavailable, or no session bus; rely on the process check instead
+	}
+	return strings.Contains(string(out), "boolean true")
+}
+
+// writeDocAtomic writes doc to path by writing a sibling tempfile and
+// renaming it over path, so a crash mid-write can never leave path corrupt.
+// The tempfile is given the original file's permissions before the rename,
+// so an in-place write doesn't silently tighten them to os.CreateTemp's
+// default 0600.
+func writeDocAtomic(doc *RhythmDBPlaylists, path string) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once the rename below succeeds
+
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := writeDocTo(doc, tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// rotateBackups shifts path.bak.1..path.bak.(count-1) up by one, dropping
+// whatever was at path.bak.count, then copies path to path.bak.1. It is a
+// no-op if path doesn't exist yet or count is non-positive.
+func rotateBackups(path string, count int) error {
+	if count <= 0 {
+		return nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	backupPath := func(n int) string { return fmt.Sprintf("%s.bak.%d", path, n) }
+
+	if err := os.Remove(backupPath(count)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for n := count - 1; n >= 1; n-- {
+		if _, err := os.Stat(backupPath(n)); os.IsNotExist(err) {
+			continue
+		}
+		if err := os.Rename(backupPath(n), backupPath(n+1)); err != nil {
+			return err
+		}
+	}
+	return copyFile(path, backupPath(1))
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// writeResult writes doc either to an explicit -out path, or in place over
+// homePath's playlists.xml (atomically, with backup rotation and a
+// Rhythmbox-is-running guard) if inPlace is set. At most one of out/inPlace
+// should be used; it does nothing if neither is set.
+func writeResult(doc *RhythmDBPlaylists, homePath, out string, inPlace bool, backups int) error {
+	if inPlace && out != "" {
+		return fmt.Errorf("-out and -inPlace are mutually exclusive")
+	}
+
+	if inPlace {
+		path := filepath.Join(homePath, playlistsRelPath)
+		if RhythmboxRunning() {
+			return fmt.Errorf("rhythmbox appears to be running; refusing to modify %s in place", path)
+		}
+		if err := rotateBackups(path, backups); err != nil {
+			return fmt.Errorf("rotating backups of %s: %w", path, err)
+		}
+		return writeDocAtomic(doc, path)
+	}
+
+	if out != "" {
+		return writeDoc(doc, out)
+	}
+	return nil
+}
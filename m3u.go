@@ -0,0 +1,135 @@
+// Import/export of M3U8 and PLS playlist files, for use with players
+// (Navidrome, mpv, VLC, ...) that don't understand Rhythmbox's playlists.xml.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WriteM3U writes p as an extended M3U8 playlist.
+func (p *Playlist) WriteM3U(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, "#EXTM3U"); err != nil {
+		return err
+	}
+	for _, l := range p.Locations {
+		if _, err := fmt.Fprintln(w, l.Text()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WritePLS writes p as a PLS playlist (the format used by winamp/XMMS-derived players).
+func (p *Playlist) WritePLS(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, "[playlist]"); err != nil {
+		return err
+	}
+	for i, l := range p.Locations {
+		n := i + 1
+		if _, err := fmt.Fprintf(w, "File%d=%s\n", n, l.Text()); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "Length%d=-1\n", n); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(w, "NumberOfEntries=%d\n", len(p.Locations)); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(w, "Version=2"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ImportM3U reads an M3U/M3U8 playlist from r and returns it as a new static
+// Playlist named name, with each entry URL-escaped into a file:// Location
+// matching what Rhythmbox itself would write.
+func ImportM3U(r io.Reader, name string) (*Playlist, error) {
+	p := &Playlist{
+		Name: name,
+		Type: "static",
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.Locations = append(p.Locations, pathToLocation(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ImportM3U: %w", err)
+	}
+
+	return p, nil
+}
+
+// pathToLocation turns a filesystem path from an M3U entry into a file://
+// Location with the same URL-escaping Rhythmbox uses: '/' stays a path
+// separator and spaces become %20, not the query-string escaping of
+// url.QueryEscape (which would turn '/' into %2F and spaces into '+').
+func pathToLocation(path string) Location {
+	u := &url.URL{Scheme: "file", Path: path}
+	return Location(u.String())
+}
+
+// importPlaylist opens path and imports it as a new static Playlist named
+// after the file's base name (without extension).
+func importPlaylist(path string) (*Playlist, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return ImportM3U(f, name)
+}
+
+// exportPlaylists writes each static playlist in playlists to dir, as both a
+// <name>.m3u8 and a <name>.pls file.
+func exportPlaylists(playlists []Playlist, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	for _, p := range playlists {
+		if p.Type != "static" {
+			continue
+		}
+
+		base := filepath.Join(dir, sanitizeFilename(p.Name))
+
+		if err := writeToFile(base+".m3u8", p.WriteM3U); err != nil {
+			return err
+		}
+		if err := writeToFile(base+".pls", p.WritePLS); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeToFile creates path and calls write with the resulting file.
+func writeToFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return write(f)
+}
+
+// sanitizeFilename replaces path separators in name so it can be used as a
+// single path component.
+func sanitizeFilename(name string) string {
+	return strings.NewReplacer("/", "_", string(filepath.Separator), "_").Replace(name)
+}
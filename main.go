@@ -4,8 +4,8 @@ package main
 import (
 	cryptorand "crypto/rand"
 	"encoding/xml"
-	"flag"
 	"fmt"
+	"io"
 	"log"
 	"math/big"
 	"math/rand"
@@ -14,7 +14,6 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
-	"time"
 )
 
 const playlistsRelPath = ".local/share/rhythmbox/playlists.xml" // relative to ${HOME}
@@ -22,12 +21,21 @@ const playlistsRelPath = ".local/share/rhythmbox/playlists.xml" // relative to $
 type RhythmDBPlaylists struct {
 	XMLName   xml.Name
 	Playlists []Playlist `xml:"playlist"`
+
+	// ExtraAttrs and ExtraElements round-trip any attribute or child element
+	// of the root <rhythmdb-playlists> element this struct doesn't model by
+	// name; see Playlist's fields of the same name.
+	ExtraAttrs    []xml.Attr   `xml:",any,attr"`
+	ExtraElements []RawElement `xml:",any"`
 }
 
 type Location string
 
 func (l Location) Text() string {
-	escapedStr, err := url.QueryUnescape(string(l))
+	// Locations are built as file:// URIs (see pathToLocation), which escape
+	// per RFC 3986 path rules: a literal '+' stays a '+'. url.QueryUnescape
+	// would turn it into a space, so use url.PathUnescape to match.
+	escapedStr, err := url.PathUnescape(string(l))
 	if err != nil {
 		log.Fatal("unescape", string(l), err)
 	}
@@ -53,95 +61,163 @@ type Playlist struct {
 	SortKey       string       `xml:"sort-key,attr,omitempty"`
 	SortDirection *int         `xml:"sort-direction,attr,omitempty"`
 	Conjunction   *Conjunction `xml:"conjunction"`
+
+	// ExtraAttrs and ExtraElements round-trip any attribute or child element
+	// this struct doesn't model by name, so an -inPlace write doesn't
+	// silently drop fields from a newer Rhythmbox schema.
+	ExtraAttrs    []xml.Attr   `xml:",any,attr"`
+	ExtraElements []RawElement `xml:",any"`
 }
 
 type Conjunction struct {
 	Data string `xml:",innerxml"`
 }
 
-var (
-	out          = flag.String("out", "", "the file path to write the processed XML to")
-	shuffleDirs  = flag.Bool("shuffleDirs", true, "whether to shuffle all dirs in playlists")
-	shuffleInDir = flag.Bool("shuffleInDir", true, "whether to shuffle the songs in one directory")
-	doDisplay    = flag.Bool("display", false, "whether to display info on static playlists")
-	doDisplayAll = flag.Bool("displayAll", false, "whether to display the song file paths as well")
-	rnd          = Seeded()
-)
+// RawElement captures one XML element verbatim, attributes and inner content
+// included, for round-tripping elements a struct doesn't know about.
+type RawElement struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Content string     `xml:",innerxml"`
+}
+
+var rnd = Seeded()
+
+const usage = `usage: rhythmtool <command> [flags] [args]
+
+commands:
+  list    [name-glob...]                 list playlists, with type and length
+  show    [-all] <name-glob...>          display info on matching playlists
+  shuffle [-all] [flags] <name-glob...>  shuffle matching playlists into new ones
+  dedupe  [-out file] <name-glob...>     remove duplicate locations in-place
+  diff    [a-glob] [b-glob]              show set differences between two playlists
+  merge   -into name [-dedupe] <glob...> concatenate matching playlists into one
+  import  [-out file] <m3u-file>         import an M3U/M3U8 file as a new static playlist
+  export  <dir> [name-glob...]           export static playlists as .m3u8/.pls files
+
+Every command that reads playlists.xml reads it from
+${HOME}/.local/share/rhythmbox/playlists.xml. Commands that produce a new or
+modified document only write it out if given -out or -inPlace; -inPlace
+refuses to run while Rhythmbox is open, writes atomically, and keeps
+rotating playlists.xml.bak.N backups (see -backups).
+`
 
 func main() {
-	flag.Parse()
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
 
-	// -displayAll=true implies -display=true
-	if *doDisplayAll {
-		*doDisplay = true
+	cmd, args := os.Args[1], os.Args[2:]
+	switch cmd {
+	case "list":
+		cmdList(args)
+	case "show":
+		cmdShow(args)
+	case "shuffle":
+		cmdShuffle(args)
+	case "dedupe":
+		cmdDedupe(args)
+	case "diff":
+		cmdDiff(args)
+	case "merge":
+		cmdMerge(args)
+	case "import":
+		cmdImport(args)
+	case "export":
+		cmdExport(args)
+	case "-h", "-help", "--help", "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "rhythmtool: unknown command %q\n\n%s", cmd, usage)
+		os.Exit(1)
 	}
+}
 
+// mustHomeDir returns the user's home directory or exits the program.
+func mustHomeDir() string {
 	homePath, err := os.UserHomeDir()
 	if err != nil {
 		log.Fatal("UserHomeDir", err)
 	}
+	return homePath
+}
+
+// loadDoc reads and parses playlists.xml from under homePath.
+func loadDoc(homePath string) (*RhythmDBPlaylists, error) {
 	playlistsPath := filepath.Join(homePath, playlistsRelPath)
 
 	inFile, err := os.Open(playlistsPath)
 	if err != nil {
-		log.Fatal("open", playlistsPath, err)
+		return nil, fmt.Errorf("open %s: %w", playlistsPath, err)
 	}
 	defer inFile.Close()
 
-	decoder := xml.NewDecoder(inFile)
 	doc := &RhythmDBPlaylists{}
-	err = decoder.Decode(doc)
+	if err := xml.NewDecoder(inFile).Decode(doc); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", playlistsPath, err)
+	}
+	return doc, nil
+}
+
+// mustLoadDoc is loadDoc, but fatal on error.
+func mustLoadDoc(homePath string) *RhythmDBPlaylists {
+	doc, err := loadDoc(homePath)
 	if err != nil {
-		log.Fatal("decode", err)
+		log.Fatal(err)
 	}
+	return doc
+}
 
-	// shuffle the first static Playlist found, saving it as a new Playlist at the end
-	// TODO: consider flags to 1) print list of playlist names, 2) select which playlist to create a shuffled copy of
-	for _, p := range doc.Playlists {
-		if p.Type != "static" {
-			continue
-		}
+// writeDoc writes doc as XML to path, matching the header Rhythmbox itself writes.
+func writeDoc(doc *RhythmDBPlaylists, path string) error {
+	outFile, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("out file: %w", err)
+	}
+	defer outFile.Close()
 
-		if *doDisplay {
-			fmt.Println("===")
-			display(&p)
-		}
+	return writeDocTo(doc, outFile)
+}
 
-		newP := p
-		randNum := rnd.Int31() % (1 << 24)
-		newP.Name += fmt.Sprintf("_SHUFFLED_%s_%d", time.Now().Format("2006-01-02"), randNum)
-		newP.Locations = shuffle(p.Locations, *shuffleDirs, *shuffleInDir)
-		doc.Playlists = append(doc.Playlists, newP)
-		break
+// writeDocTo encodes doc as XML to w, matching the header Rhythmbox itself writes.
+func writeDocTo(doc *RhythmDBPlaylists, w io.Writer) error {
+	if _, err := io.WriteString(w, `<?xml version="1.0"?>`+"\n"); err != nil {
+		return fmt.Errorf("write header: %w", err)
 	}
 
-	// output the XML
-	if *out != "" {
-		outFile, err := os.Create(*out)
-		if err != nil {
-			log.Fatal("out file", err)
-		}
-		defer outFile.Close()
-
-		// write header in case that matters
-		_, err = outFile.WriteString(`<?xml version="1.0"?>` + "\n")
-		if err != nil {
-			log.Fatal("out file: write header", err)
-		}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("encode: %w", err)
+	}
+	return nil
+}
 
-		encoder := xml.NewEncoder(outFile)
-		encoder.Indent("", "  ")
-		err = encoder.Encode(doc)
-		if err != nil {
-			log.Fatal("encode", err)
+// resolveLocations returns p's track locations, materializing them against
+// rhythmdb.xml (loaded lazily into *trackDB) if p is an automatic playlist.
+func resolveLocations(p *Playlist, homePath string, trackDB **TrackDB) ([]Location, error) {
+	switch p.Type {
+	case "static":
+		return p.Locations, nil
+	case "automatic":
+		if *trackDB == nil {
+			db, err := LoadTrackDB(homePath)
+			if err != nil {
+				return nil, err
+			}
+			*trackDB = db
 		}
+		return MaterializePlaylist(p, *trackDB)
+	default:
+		return nil, fmt.Errorf("resolveLocations: unsupported playlist type %q", p.Type)
 	}
 }
 
-func display(p *Playlist) {
-	fmt.Println("len(Locations):", len(p.Locations))
-	if *doDisplayAll {
-		for _, l := range p.Locations {
+func display(p *Playlist, locs []Location, showPaths bool) {
+	fmt.Println("len(Locations):", len(locs))
+	if showPaths {
+		for _, l := range locs {
 			fmt.Println("  ", l.Text())
 		}
 	}
@@ -151,7 +227,9 @@ func display(p *Playlist) {
 	fmt.Println("SearchType:", p.SearchType)
 	fmt.Println("Type:", p.Type)
 	fmt.Println("SortKey:", p.SortKey)
-	fmt.Println("SortDirection:", *p.SortDirection)
+	if p.SortDirection != nil {
+		fmt.Println("SortDirection:", *p.SortDirection)
+	}
 	if p.Conjunction != nil {
 		fmt.Println("Conjunction:", *p.Conjunction)
 	}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+// TestRoundTripUnknownXML checks that attributes and elements this tool
+// doesn't model by name survive a decode/encode round trip, both on a
+// <playlist> and on the <rhythmdb-playlists> root itself.
+func TestRoundTripUnknownXML(t *testing.T) {
+	const input = `<rhythmdb-playlists future-root-attr="1">` +
+		`<playlist name="Favorites" show-browser="0" browser-position="0" search-type="search-match" type="static" future-playlist-attr="2">` +
+		`<location>file:///music/a.mp3</location>` +
+		`<future-child-element present="yes">hello</future-child-element>` +
+		`</playlist>` +
+		`</rhythmdb-playlists>`
+
+	var doc RhythmDBPlaylists
+	if err := xml.Unmarshal([]byte(input), &doc); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if len(doc.ExtraAttrs) != 1 || doc.ExtraAttrs[0].Name.Local != "future-root-attr" || doc.ExtraAttrs[0].Value != "1" {
+		t.Errorf("unexpected root ExtraAttrs: %+v", doc.ExtraAttrs)
+	}
+	if len(doc.Playlists) != 1 {
+		t.Fatalf("len(doc.Playlists) = %d, want 1", len(doc.Playlists))
+	}
+	p := doc.Playlists[0]
+	if len(p.ExtraAttrs) != 1 || p.ExtraAttrs[0].Name.Local != "future-playlist-attr" || p.ExtraAttrs[0].Value != "2" {
+		t.Errorf("unexpected playlist ExtraAttrs: %+v", p.ExtraAttrs)
+	}
+	if len(p.ExtraElements) != 1 || p.ExtraElements[0].XMLName.Local != "future-child-element" {
+		t.Fatalf("unexpected playlist ExtraElements: %+v", p.ExtraElements)
+	}
+	if len(p.ExtraElements[0].Attrs) != 1 || p.ExtraElements[0].Attrs[0].Value != "yes" {
+		t.Errorf("unexpected ExtraElement attrs: %+v", p.ExtraElements[0].Attrs)
+	}
+	if p.ExtraElements[0].Content != "hello" {
+		t.Errorf("ExtraElement.Content = %q, want %q", p.ExtraElements[0].Content, "hello")
+	}
+
+	var out strings.Builder
+	if err := writeDocTo(&doc, &out); err != nil {
+		t.Fatalf("writeDocTo: %v", err)
+	}
+
+	for _, want := range []string{
+		`future-root-attr="1"`,
+		`future-playlist-attr="2"`,
+		`future-child-element`,
+		`present="yes"`,
+		`hello`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("round-tripped XML missing %q:\n%s", want, out.String())
+		}
+	}
+}
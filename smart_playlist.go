@@ -0,0 +1,221 @@
+// Evaluation of Rhythmbox "automatic" (smart) playlists, whose rules are
+// encoded as a <conjunction> of criteria against the track database in
+// rhythmdb.xml rather than a static list of locations.
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const rhythmdbRelPath = ".local/share/rhythmbox/rhythmdb.xml" // relative to ${HOME}
+
+// TrackDB is the subset of rhythmdb.xml needed to evaluate smart playlists.
+type TrackDB struct {
+	XMLName xml.Name `xml:"rhythmdb"`
+	Tracks  []Track  `xml:"entry"`
+}
+
+// Track is one <entry type="song"> in rhythmdb.xml.
+type Track struct {
+	Type        string   `xml:"type,attr"`
+	Location    Location `xml:"location"`
+	Artist      string   `xml:"artist"`
+	Album       string   `xml:"album"`
+	Genre       string   `xml:"genre"`
+	Title       string   `xml:"title"`
+	TrackNumber int      `xml:"track-number"`
+	Year        int      `xml:"year"`
+	Rating      int      `xml:"rating"`
+	PlayCount   int      `xml:"play-count"`
+	LastPlayed  int64    `xml:"last-played"`
+	FirstSeen   int64    `xml:"first-seen"`
+}
+
+// field returns the value of the named rhythmdb property, stringified so
+// Criterion can compare it uniformly regardless of the property's Go type.
+func (t Track) field(name string) string {
+	switch name {
+	case "artist":
+		return t.Artist
+	case "album":
+		return t.Album
+	case "genre":
+		return t.Genre
+	case "title":
+		return t.Title
+	case "track-number":
+		return strconv.Itoa(t.TrackNumber)
+	case "year":
+		return strconv.Itoa(t.Year)
+	case "rating":
+		return strconv.Itoa(t.Rating)
+	case "play-count":
+		return strconv.Itoa(t.PlayCount)
+	case "last-played":
+		return strconv.FormatInt(t.LastPlayed, 10)
+	case "first-seen":
+		return strconv.FormatInt(t.FirstSeen, 10)
+	default:
+		return ""
+	}
+}
+
+// Criterion is one leaf test of a smart playlist's rule, e.g.
+// <like><string>artist</string><string>Rush</string></like>.
+type Criterion struct {
+	Op    string // "like", "equals", "greater", "less", "current-time-within"
+	Field string
+	Value string
+}
+
+// UnmarshalXML captures the element name as Op and its child text nodes as
+// Field/Value, since Rhythmbox encodes criteria as <op><string>field</string>
+// <string-or-long-or-double>value</string-or-long-or-double></op>.
+func (c *Criterion) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	c.Op = start.Name.Local
+
+	var values []string
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			var v string
+			if err := d.DecodeElement(&v, &t); err != nil {
+				return err
+			}
+			values = append(values, v)
+		case xml.EndElement:
+			if t.Name == start.Name {
+				if len(values) > 0 {
+					c.Field = values[0]
+				}
+				if len(values) > 1 {
+					c.Value = values[1]
+				}
+				return nil
+			}
+		}
+	}
+}
+
+// Matches reports whether t satisfies c, evaluated as of now.
+func (c Criterion) Matches(t Track, now time.Time) bool {
+	switch c.Op {
+	case "like":
+		return strings.Contains(strings.ToLower(t.field(c.Field)), strings.ToLower(c.Value))
+	case "equals":
+		return t.field(c.Field) == c.Value
+	case "greater":
+		tv, err1 := strconv.ParseFloat(t.field(c.Field), 64)
+		cv, err2 := strconv.ParseFloat(c.Value, 64)
+		return err1 == nil && err2 == nil && tv > cv
+	case "less":
+		tv, err1 := strconv.ParseFloat(t.field(c.Field), 64)
+		cv, err2 := strconv.ParseFloat(c.Value, 64)
+		return err1 == nil && err2 == nil && tv < cv
+	case "current-time-within":
+		within, err1 := strconv.ParseInt(c.Value, 10, 64)
+		ts, err2 := strconv.ParseInt(t.field(c.Field), 10, 64)
+		return err1 == nil && err2 == nil && now.Unix()-ts <= within
+	default:
+		return false
+	}
+}
+
+// SubQuery is a <subquery>: its Criteria are ANDed together.
+type SubQuery struct {
+	Criteria []Criterion `xml:",any"`
+}
+
+func (s SubQuery) Matches(t Track, now time.Time) bool {
+	for _, c := range s.Criteria {
+		if !c.Matches(t, now) {
+			return false
+		}
+	}
+	return true
+}
+
+// Rule is the parsed form of a Conjunction: a top-level AND of its direct
+// Criteria and (if any SubQueries are present) at least one of them.
+//
+// This mirrors Rhythmbox's own semantics closely enough for the playlists we
+// care about evaluating; it does not attempt to support arbitrarily nested
+// <conjunction> groups inside a <subquery>.
+type Rule struct {
+	SubQueries []SubQuery  `xml:"subquery"`
+	Criteria   []Criterion `xml:",any"`
+}
+
+func (r Rule) Matches(t Track, now time.Time) bool {
+	for _, c := range r.Criteria {
+		if !c.Matches(t, now) {
+			return false
+		}
+	}
+	if len(r.SubQueries) == 0 {
+		return true
+	}
+	for _, sq := range r.SubQueries {
+		if sq.Matches(t, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseRule parses c's raw innerxml into a Rule.
+func (c *Conjunction) ParseRule() (*Rule, error) {
+	var r Rule
+	wrapped := "<conjunction>" + c.Data + "</conjunction>"
+	if err := xml.Unmarshal([]byte(wrapped), &r); err != nil {
+		return nil, fmt.Errorf("ParseRule: %w", err)
+	}
+	return &r, nil
+}
+
+// LoadTrackDB reads and parses the Rhythmbox track database found under
+// homePath (typically the user's home directory).
+func LoadTrackDB(homePath string) (*TrackDB, error) {
+	f, err := os.Open(filepath.Join(homePath, rhythmdbRelPath))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	db := &TrackDB{}
+	if err := xml.NewDecoder(f).Decode(db); err != nil {
+		return nil, fmt.Errorf("LoadTrackDB: %w", err)
+	}
+	return db, nil
+}
+
+// MaterializePlaylist evaluates p's rule (p.Type must be "automatic") against
+// every track in db, returning the matching Locations in database order.
+func MaterializePlaylist(p *Playlist, db *TrackDB) ([]Location, error) {
+	if p.Conjunction == nil {
+		return nil, fmt.Errorf("MaterializePlaylist: %q has no conjunction to evaluate", p.Name)
+	}
+	rule, err := p.Conjunction.ParseRule()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var locs []Location
+	for _, t := range db.Tracks {
+		if rule.Matches(t, now) {
+			locs = append(locs, t.Location)
+		}
+	}
+	return locs, nil
+}
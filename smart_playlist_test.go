@@ -0,0 +1,138 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCriterionMatches(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	track := Track{
+		Artist:     "Rush",
+		Rating:     4,
+		PlayCount:  10,
+		LastPlayed: now.Add(-2 * time.Hour).Unix(),
+	}
+
+	tests := []struct {
+		name string
+		c    Criterion
+		want bool
+	}{
+		{"like match, case-insensitive", Criterion{Op: "like", Field: "artist", Value: "rus"}, true},
+		{"like no match", Criterion{Op: "like", Field: "artist", Value: "beatles"}, false},
+		{"equals match", Criterion{Op: "equals", Field: "artist", Value: "Rush"}, true},
+		{"equals no match", Criterion{Op: "equals", Field: "artist", Value: "rush"}, false},
+		{"greater true", Criterion{Op: "greater", Field: "rating", Value: "3"}, true},
+		{"greater false", Criterion{Op: "greater", Field: "rating", Value: "4"}, false},
+		{"less true", Criterion{Op: "less", Field: "play-count", Value: "20"}, true},
+		{"less false", Criterion{Op: "less", Field: "play-count", Value: "10"}, false},
+		{"current-time-within true", Criterion{Op: "current-time-within", Field: "last-played", Value: "10800"}, true},
+		{"current-time-within false", Criterion{Op: "current-time-within", Field: "last-played", Value: "60"}, false},
+		{"unknown op", Criterion{Op: "bogus", Field: "artist", Value: "Rush"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.c.Matches(track, now); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	track := Track{Artist: "Rush", Genre: "Rock", Rating: 5}
+
+	tests := []struct {
+		name string
+		rule Rule
+		want bool
+	}{
+		{
+			name: "criteria only, all match",
+			rule: Rule{Criteria: []Criterion{
+				{Op: "equals", Field: "artist", Value: "Rush"},
+				{Op: "equals", Field: "genre", Value: "Rock"},
+			}},
+			want: true,
+		},
+		{
+			name: "criteria only, one fails",
+			rule: Rule{Criteria: []Criterion{
+				{Op: "equals", Field: "artist", Value: "Rush"},
+				{Op: "equals", Field: "genre", Value: "Jazz"},
+			}},
+			want: false,
+		},
+		{
+			name: "no subqueries defaults to true",
+			rule: Rule{},
+			want: true,
+		},
+		{
+			name: "subquery must match at least one",
+			rule: Rule{SubQueries: []SubQuery{
+				{Criteria: []Criterion{{Op: "equals", Field: "artist", Value: "Beatles"}}},
+				{Criteria: []Criterion{{Op: "equals", Field: "artist", Value: "Rush"}}},
+			}},
+			want: true,
+		},
+		{
+			name: "no subquery matches",
+			rule: Rule{SubQueries: []SubQuery{
+				{Criteria: []Criterion{{Op: "equals", Field: "artist", Value: "Beatles"}}},
+			}},
+			want: false,
+		},
+		{
+			name: "criteria pass but no subquery matches",
+			rule: Rule{
+				Criteria:   []Criterion{{Op: "equals", Field: "genre", Value: "Rock"}},
+				SubQueries: []SubQuery{{Criteria: []Criterion{{Op: "equals", Field: "artist", Value: "Beatles"}}}},
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Matches(track, now); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubQueryMatches(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	track := Track{Artist: "Rush", Genre: "Rock"}
+
+	sq := SubQuery{Criteria: []Criterion{
+		{Op: "equals", Field: "artist", Value: "Rush"},
+		{Op: "equals", Field: "genre", Value: "Rock"},
+	}}
+	if !sq.Matches(track, now) {
+		t.Error("expected SubQuery to match when all criteria match")
+	}
+
+	sq.Criteria = append(sq.Criteria, Criterion{Op: "equals", Field: "genre", Value: "Jazz"})
+	if sq.Matches(track, now) {
+		t.Error("expected SubQuery not to match once one criterion fails")
+	}
+}
+
+func TestConjunctionParseRule(t *testing.T) {
+	c := &Conjunction{Data: `<equals><string>artist</string><string>Rush</string></equals>` +
+		`<subquery><equals><string>genre</string><string>Rock</string></equals></subquery>`}
+
+	rule, err := c.ParseRule()
+	if err != nil {
+		t.Fatalf("ParseRule: %v", err)
+	}
+	if len(rule.Criteria) != 1 || rule.Criteria[0].Op != "equals" || rule.Criteria[0].Field != "artist" || rule.Criteria[0].Value != "Rush" {
+		t.Errorf("unexpected top-level criteria: %+v", rule.Criteria)
+	}
+	if len(rule.SubQueries) != 1 || len(rule.SubQueries[0].Criteria) != 1 {
+		t.Errorf("unexpected subqueries: %+v", rule.SubQueries)
+	}
+}
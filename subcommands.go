@@ -0,0 +1,388 @@
+// Subcommand implementations for the rhythmtool CLI.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+)
+
+// addOutputFlags registers the -out/-inPlace/-backups flags shared by every
+// subcommand that can produce an updated playlists.xml.
+func addOutputFlags(fs *flag.FlagSet) (out *string, inPlace *bool, backups *int) {
+	out = fs.String("out", "", "the file path to write the updated XML to")
+	inPlace = fs.Bool("inPlace", false, "write the update directly back to playlists.xml, atomically and with backup rotation")
+	backups = fs.Int("backups", 5, "number of rotating playlists.xml.bak.N backups to keep with -inPlace")
+	return out, inPlace, backups
+}
+
+// writeOutput writes doc per -out/-inPlace, doing nothing if neither was given.
+func writeOutput(doc *RhythmDBPlaylists, homePath string, out *string, inPlace *bool, backups *int) {
+	if *out == "" && !*inPlace {
+		return
+	}
+	if err := writeResult(doc, homePath, *out, *inPlace, *backups); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// matchPlaylists returns the indices of playlists whose Name matches at
+// least one of patterns. With no patterns, every playlist matches.
+func matchPlaylists(playlists []Playlist, patterns []string) []int {
+	if len(patterns) == 0 {
+		idxs := make([]int, len(playlists))
+		for i := range playlists {
+			idxs[i] = i
+		}
+		return idxs
+	}
+
+	var idxs []int
+	for i, p := range playlists {
+		for _, pat := range patterns {
+			if ok, err := filepath.Match(pat, p.Name); err == nil && ok {
+				idxs = append(idxs, i)
+				break
+			}
+		}
+	}
+	return idxs
+}
+
+func cmdList(args []string) {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	fs.Parse(args)
+
+	homePath := mustHomeDir()
+	doc := mustLoadDoc(homePath)
+
+	for _, i := range matchPlaylists(doc.Playlists, fs.Args()) {
+		p := doc.Playlists[i]
+		fmt.Printf("%-40s %-10s %d tracks\n", p.Name, p.Type, len(p.Locations))
+	}
+}
+
+func cmdShow(args []string) {
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	all := fs.Bool("all", false, "also print each track's file path")
+	fs.Parse(args)
+
+	if len(fs.Args()) == 0 {
+		log.Fatal("show: need at least one playlist name/glob")
+	}
+
+	homePath := mustHomeDir()
+	doc := mustLoadDoc(homePath)
+
+	var trackDB *TrackDB
+	for _, i := range matchPlaylists(doc.Playlists, fs.Args()) {
+		p := &doc.Playlists[i]
+		locs, err := resolveLocations(p, homePath, &trackDB)
+		if err != nil {
+			log.Fatal("show: ", err)
+		}
+		fmt.Println("===")
+		display(p, locs, *all)
+	}
+}
+
+// ShuffleOptions holds the flags that govern how shuffle mixes up a playlist's locations.
+type ShuffleOptions struct {
+	ShuffleDirs         bool
+	ShuffleInDir        bool
+	GroupBy             string
+	AvoidAdjacentArtist bool
+	ShuffleMode         string
+	HalfLife            float64
+	RecencyTau          float64
+	RatingBoost         float64
+}
+
+// shuffleLocations applies opts to locs, lazily loading the track database or
+// tag reader that a given mode needs.
+func shuffleLocations(locs []Location, homePath string, opts ShuffleOptions, trackDB **TrackDB, tagReader *CachingTagReader) ([]Location, error) {
+	var shuffled []Location
+	switch {
+	case opts.GroupBy != "":
+		var err error
+		shuffled, err = ShuffleByTags(locs, tagReader, opts.GroupBy)
+		if err != nil {
+			return nil, err
+		}
+	case opts.ShuffleMode == "weighted":
+		if *trackDB == nil {
+			db, err := LoadTrackDB(homePath)
+			if err != nil {
+				log.Println("weighted shuffle: rhythmdb unavailable, falling back to uniform shuffle:", err)
+			} else {
+				*trackDB = db
+			}
+		}
+		if *trackDB != nil {
+			shuffled = WeightedShuffle(locs, *trackDB, opts.HalfLife, opts.RecencyTau, opts.RatingBoost)
+		} else {
+			shuffled = shuffle(locs, opts.ShuffleDirs, opts.ShuffleInDir)
+		}
+	default:
+		shuffled = shuffle(locs, opts.ShuffleDirs, opts.ShuffleInDir)
+	}
+
+	if opts.AvoidAdjacentArtist {
+		shuffled = AvoidAdjacentArtist(shuffled, tagReader, len(shuffled)*4)
+	}
+	return shuffled, nil
+}
+
+func cmdShuffle(args []string) {
+	fs := flag.NewFlagSet("shuffle", flag.ExitOnError)
+	all := fs.Bool("all", false, "shuffle every static/automatic playlist")
+	opts := ShuffleOptions{}
+	fs.BoolVar(&opts.ShuffleDirs, "shuffleDirs", true, "whether to shuffle all dirs in playlists")
+	fs.BoolVar(&opts.ShuffleInDir, "shuffleInDir", true, "whether to shuffle the songs in one directory")
+	fs.StringVar(&opts.GroupBy, "groupBy", "", `group the shuffle by audio tags instead of directory: "album" or "artist"`)
+	fs.BoolVar(&opts.AvoidAdjacentArtist, "avoidAdjacentArtist", false, "swap-repair the shuffle so no two consecutive tracks share an artist tag")
+	fs.StringVar(&opts.ShuffleMode, "shuffleMode", "directory", `"directory" (default) or "weighted", which favors higher-rated, less-recently-played tracks using rhythmdb.xml play history`)
+	fs.Float64Var(&opts.HalfLife, "halfLife", 20, "weighted shuffle: play count at which a track's weight has decayed by ~63%")
+	fs.Float64Var(&opts.RecencyTau, "recencyTau", 30, "weighted shuffle: time constant in days for the recency term")
+	fs.Float64Var(&opts.RatingBoost, "ratingBoost", 1, "weighted shuffle: multiplier applied to a track's star rating")
+	out, inPlace, backups := addOutputFlags(fs)
+	fs.Parse(args)
+
+	if !*all && len(fs.Args()) == 0 {
+		log.Fatal("shuffle: need -all or at least one playlist name/glob")
+	}
+
+	homePath := mustHomeDir()
+	doc := mustLoadDoc(homePath)
+
+	var idxs []int
+	if *all {
+		for i, p := range doc.Playlists {
+			if p.Type == "static" || p.Type == "automatic" {
+				idxs = append(idxs, i)
+			}
+		}
+	} else {
+		for _, i := range matchPlaylists(doc.Playlists, fs.Args()) {
+			if doc.Playlists[i].Type == "static" || doc.Playlists[i].Type == "automatic" {
+				idxs = append(idxs, i)
+			}
+		}
+	}
+
+	var trackDB *TrackDB
+	var tagReader *CachingTagReader
+	if opts.GroupBy != "" || opts.AvoidAdjacentArtist {
+		tagReader = NewCachingTagReader(DefaultTagReader, filepath.Join(homePath, tagCacheRelPath))
+		defer func() {
+			if err := tagReader.Save(); err != nil {
+				log.Println("tag cache: save:", err)
+			}
+		}()
+	}
+
+	for _, i := range idxs {
+		p := doc.Playlists[i]
+		locs, err := resolveLocations(&p, homePath, &trackDB)
+		if err != nil {
+			log.Fatal("shuffle: ", err)
+		}
+
+		shuffled, err := shuffleLocations(locs, homePath, opts, &trackDB, tagReader)
+		if err != nil {
+			log.Fatal("shuffle: ", err)
+		}
+
+		newP := p
+		randNum := rnd.Int31() % (1 << 24)
+		newP.Name += fmt.Sprintf("_SHUFFLED_%s_%d", time.Now().Format("2006-01-02"), randNum)
+		newP.Type = "static"
+		newP.Conjunction = nil
+		newP.Locations = shuffled
+		doc.Playlists = append(doc.Playlists, newP)
+	}
+
+	writeOutput(doc, homePath, out, inPlace, backups)
+}
+
+// dedupeLocations removes duplicate Locations, keeping the first occurrence of each.
+func dedupeLocations(locs []Location) []Location {
+	seen := make(map[Location]bool, len(locs))
+	result := make([]Location, 0, len(locs))
+	for _, l := range locs {
+		if seen[l] {
+			continue
+		}
+		seen[l] = true
+		result = append(result, l)
+	}
+	return result
+}
+
+func cmdDedupe(args []string) {
+	fs := flag.NewFlagSet("dedupe", flag.ExitOnError)
+	out, inPlace, backups := addOutputFlags(fs)
+	fs.Parse(args)
+
+	if len(fs.Args()) == 0 {
+		log.Fatal("dedupe: need at least one playlist name/glob")
+	}
+
+	homePath := mustHomeDir()
+	doc := mustLoadDoc(homePath)
+
+	for _, i := range matchPlaylists(doc.Playlists, fs.Args()) {
+		p := &doc.Playlists[i]
+		if p.Type != "static" {
+			log.Printf("dedupe: skipping %q: not a static playlist", p.Name)
+			continue
+		}
+		p.Locations = dedupeLocations(p.Locations)
+	}
+
+	writeOutput(doc, homePath, out, inPlace, backups)
+}
+
+func cmdDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fs.Parse(args)
+
+	if len(fs.Args()) != 2 {
+		log.Fatal("diff: need exactly two playlist names/globs")
+	}
+
+	homePath := mustHomeDir()
+	doc := mustLoadDoc(homePath)
+
+	var trackDB *TrackDB
+	locsA, err := locationsForPattern(doc.Playlists, fs.Args()[0], homePath, &trackDB)
+	if err != nil {
+		log.Fatal("diff: ", err)
+	}
+	locsB, err := locationsForPattern(doc.Playlists, fs.Args()[1], homePath, &trackDB)
+	if err != nil {
+		log.Fatal("diff: ", err)
+	}
+
+	inA := make(map[Location]bool, len(locsA))
+	for _, l := range locsA {
+		inA[l] = true
+	}
+	inB := make(map[Location]bool, len(locsB))
+	for _, l := range locsB {
+		inB[l] = true
+	}
+
+	fmt.Printf("only in %s:\n", fs.Args()[0])
+	for _, l := range locsA {
+		if !inB[l] {
+			fmt.Println("  ", l.Text())
+		}
+	}
+	fmt.Printf("only in %s:\n", fs.Args()[1])
+	for _, l := range locsB {
+		if !inA[l] {
+			fmt.Println("  ", l.Text())
+		}
+	}
+}
+
+func cmdMerge(args []string) {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	into := fs.String("into", "", "name for the merged playlist")
+	dedupe := fs.Bool("dedupe", false, "remove duplicate locations from the merge")
+	out, inPlace, backups := addOutputFlags(fs)
+	fs.Parse(args)
+
+	if *into == "" {
+		log.Fatal("merge: -into is required")
+	}
+	if len(fs.Args()) < 2 {
+		log.Fatal("merge: need at least two playlist names/globs")
+	}
+
+	homePath := mustHomeDir()
+	doc := mustLoadDoc(homePath)
+
+	var trackDB *TrackDB
+	var merged []Location
+	for _, pat := range fs.Args() {
+		locs, err := locationsForPattern(doc.Playlists, pat, homePath, &trackDB)
+		if err != nil {
+			log.Fatal("merge: ", err)
+		}
+		merged = append(merged, locs...)
+	}
+
+	if *dedupe {
+		merged = dedupeLocations(merged)
+	}
+
+	doc.Playlists = append(doc.Playlists, Playlist{
+		Name:      *into,
+		Type:      "static",
+		Locations: merged,
+	})
+
+	writeOutput(doc, homePath, out, inPlace, backups)
+}
+
+// locationsForPattern resolves and concatenates the locations of every
+// playlist matching pattern, in document order.
+func locationsForPattern(playlists []Playlist, pattern string, homePath string, trackDB **TrackDB) ([]Location, error) {
+	var locs []Location
+	for _, i := range matchPlaylists(playlists, []string{pattern}) {
+		l, err := resolveLocations(&playlists[i], homePath, trackDB)
+		if err != nil {
+			return nil, err
+		}
+		locs = append(locs, l...)
+	}
+	return locs, nil
+}
+
+func cmdImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	out, inPlace, backups := addOutputFlags(fs)
+	fs.Parse(args)
+
+	if len(fs.Args()) != 1 {
+		log.Fatal("import: need exactly one M3U/M3U8 file")
+	}
+
+	homePath := mustHomeDir()
+	doc := mustLoadDoc(homePath)
+
+	newP, err := importPlaylist(fs.Args()[0])
+	if err != nil {
+		log.Fatal("import: ", err)
+	}
+	doc.Playlists = append(doc.Playlists, *newP)
+
+	writeOutput(doc, homePath, out, inPlace, backups)
+}
+
+func cmdExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	fs.Parse(args)
+
+	if len(fs.Args()) == 0 {
+		log.Fatal("export: need a destination directory")
+	}
+	dir := fs.Args()[0]
+	patterns := fs.Args()[1:]
+
+	homePath := mustHomeDir()
+	doc := mustLoadDoc(homePath)
+
+	var playlists []Playlist
+	for _, i := range matchPlaylists(doc.Playlists, patterns) {
+		playlists = append(playlists, doc.Playlists[i])
+	}
+
+	if err := exportPlaylists(playlists, dir); err != nil {
+		log.Fatal("export: ", err)
+	}
+}
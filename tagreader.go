@@ -0,0 +1,133 @@
+// Reading audio tags (artist, album, genre, year, track number) from the
+// files a Location points to, for tag-aware shuffle modes.
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/dhowden/tag"
+)
+
+const tagCacheRelPath = ".cache/rhythmtool/tags.json" // relative to ${HOME}
+
+// Tags holds the subset of a track's metadata that shuffle modes care about.
+type Tags struct {
+	Artist string
+	Album  string
+	Genre  string
+	Year   int
+	Track  int
+}
+
+// TagReader reads the Tags embedded in the audio file at path.
+type TagReader interface {
+	ReadTags(path string) (Tags, error)
+}
+
+// fileTagReader is the default TagReader, backed by github.com/dhowden/tag.
+type fileTagReader struct{}
+
+// DefaultTagReader reads ID3v1/v2, MP4, FLAC and OGG tags directly from disk.
+var DefaultTagReader TagReader = fileTagReader{}
+
+func (fileTagReader) ReadTags(path string) (Tags, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Tags{}, err
+	}
+	defer f.Close()
+
+	m, err := tag.ReadFrom(f)
+	if err != nil {
+		return Tags{}, err
+	}
+
+	track, _ := m.Track()
+	return Tags{
+		Artist: m.Artist(),
+		Album:  m.Album(),
+		Genre:  m.Genre(),
+		Year:   m.Year(),
+		Track:  track,
+	}, nil
+}
+
+// cacheEntry is one record in the on-disk tag cache, keyed by file path and
+// invalidated whenever the file's mtime or size changes.
+type cacheEntry struct {
+	ModTime int64
+	Size    int64
+	Tags    Tags
+}
+
+// CachingTagReader wraps another TagReader with an on-disk cache keyed by
+// (path, mtime, size), so repeated runs over large libraries don't re-parse
+// every file's tags.
+type CachingTagReader struct {
+	Reader TagReader
+
+	path    string
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	dirty   bool
+}
+
+// NewCachingTagReader loads whatever cache already exists at cachePath (a
+// missing or corrupt cache just means starting cold, not an error).
+func NewCachingTagReader(reader TagReader, cachePath string) *CachingTagReader {
+	c := &CachingTagReader{
+		Reader:  reader,
+		path:    cachePath,
+		entries: make(map[string]cacheEntry),
+	}
+	if data, err := os.ReadFile(cachePath); err == nil {
+		_ = json.Unmarshal(data, &c.entries)
+	}
+	return c
+}
+
+func (c *CachingTagReader) ReadTags(path string) (Tags, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Tags{}, err
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[path]
+	c.mu.Unlock()
+	if ok && entry.ModTime == info.ModTime().Unix() && entry.Size == info.Size() {
+		return entry.Tags, nil
+	}
+
+	tags, err := c.Reader.ReadTags(path)
+	if err != nil {
+		return Tags{}, err
+	}
+
+	c.mu.Lock()
+	c.entries[path] = cacheEntry{ModTime: info.ModTime().Unix(), Size: info.Size(), Tags: tags}
+	c.dirty = true
+	c.mu.Unlock()
+	return tags, nil
+}
+
+// Save persists the cache to disk, if anything changed since it was loaded.
+func (c *CachingTagReader) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0o644)
+}
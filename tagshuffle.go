@@ -0,0 +1,166 @@
+// Tag-aware shuffle modes that group locations by album or artist using
+// real audio metadata rather than directory layout.
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// taggedLocation pairs a Location with the Tags read from the file it points to.
+type taggedLocation struct {
+	Loc  Location
+	Tags Tags
+}
+
+// readTagged reads the tags for each Location, tolerating individual read
+// failures by falling back to a zero-value Tags (an untagged or unreadable
+// file just won't group or sort meaningfully).
+func readTagged(locations []Location, reader TagReader) []taggedLocation {
+	tagged := make([]taggedLocation, len(locations))
+	for i, l := range locations {
+		tags, err := reader.ReadTags(l.Text())
+		if err != nil {
+			tags = Tags{}
+		}
+		tagged[i] = taggedLocation{Loc: l, Tags: tags}
+	}
+	return tagged
+}
+
+// ShuffleByTags groups locations using their tags and shuffles according to
+// groupBy:
+//
+//	"album"  - shuffles album order; tracks within an album stay in order.
+//	"artist" - shuffles artist order; each artist's albums stay together and
+//	           in order, and tracks within an album stay in order.
+func ShuffleByTags(locations []Location, reader TagReader, groupBy string) ([]Location, error) {
+	tagged := readTagged(locations, reader)
+
+	switch groupBy {
+	case "album":
+		return shuffleByAlbum(tagged), nil
+	case "artist":
+		return shuffleByArtist(tagged), nil
+	default:
+		return nil, fmt.Errorf("ShuffleByTags: unknown groupBy %q", groupBy)
+	}
+}
+
+func albumKey(t Tags) string {
+	return t.Artist + "\x00" + t.Album
+}
+
+// sortByTrack orders a single album's tracks by track number.
+func sortByTrack(group []taggedLocation) {
+	sort.SliceStable(group, func(i, j int) bool {
+		return group[i].Tags.Track < group[j].Tags.Track
+	})
+}
+
+func shuffleByAlbum(tagged []taggedLocation) []Location {
+	albums := make(map[string][]taggedLocation)
+	var order []string
+	for _, tl := range tagged {
+		key := albumKey(tl.Tags)
+		if _, ok := albums[key]; !ok {
+			order = append(order, key)
+		}
+		albums[key] = append(albums[key], tl)
+	}
+
+	rnd.Shuffle(len(order), func(i, j int) {
+		order[i], order[j] = order[j], order[i]
+	})
+
+	var result []Location
+	for _, key := range order {
+		group := albums[key]
+		sortByTrack(group)
+		for _, tl := range group {
+			result = append(result, tl.Loc)
+		}
+	}
+	return result
+}
+
+func shuffleByArtist(tagged []taggedLocation) []Location {
+	albums := make(map[string][]taggedLocation) // albumKey -> tracks
+	var artistOrder []string
+	artistAlbums := make(map[string][]string) // artist -> album keys, in first-seen order
+	seenArtist := make(map[string]bool)
+	seenAlbum := make(map[string]bool)
+
+	for _, tl := range tagged {
+		artist := tl.Tags.Artist
+		if !seenArtist[artist] {
+			seenArtist[artist] = true
+			artistOrder = append(artistOrder, artist)
+		}
+		key := albumKey(tl.Tags)
+		if !seenAlbum[key] {
+			seenAlbum[key] = true
+			artistAlbums[artist] = append(artistAlbums[artist], key)
+		}
+		albums[key] = append(albums[key], tl)
+	}
+
+	rnd.Shuffle(len(artistOrder), func(i, j int) {
+		artistOrder[i], artistOrder[j] = artistOrder[j], artistOrder[i]
+	})
+
+	var result []Location
+	for _, artist := range artistOrder {
+		for _, key := range artistAlbums[artist] {
+			group := albums[key]
+			sortByTrack(group)
+			for _, tl := range group {
+				result = append(result, tl.Loc)
+			}
+		}
+	}
+	return result
+}
+
+// AvoidAdjacentArtist post-processes a shuffle to swap away any two
+// consecutive tracks that share an artist, making at most maxAttempts swaps.
+// Conflicts with no safe swap candidate (e.g. one artist making up most of
+// the playlist) are left in place.
+func AvoidAdjacentArtist(locations []Location, reader TagReader, maxAttempts int) []Location {
+	tagged := readTagged(locations, reader)
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		conflict := -1
+		for i := 1; i < len(tagged); i++ {
+			if tagged[i].Tags.Artist != "" && tagged[i].Tags.Artist == tagged[i-1].Tags.Artist {
+				conflict = i
+				break
+			}
+		}
+		if conflict == -1 {
+			break
+		}
+
+		swapped := false
+		for j := conflict + 1; j < len(tagged); j++ {
+			if tagged[j].Tags.Artist == tagged[conflict].Tags.Artist {
+				continue // swapping here would just move the conflict to j
+			}
+			if tagged[j].Tags.Artist == tagged[conflict-1].Tags.Artist {
+				continue // swapping here would create a new conflict at conflict-1
+			}
+			tagged[conflict], tagged[j] = tagged[j], tagged[conflict]
+			swapped = true
+			break
+		}
+		if !swapped {
+			break
+		}
+	}
+
+	result := make([]Location, len(tagged))
+	for i, tl := range tagged {
+		result[i] = tl.Loc
+	}
+	return result
+}
@@ -0,0 +1,94 @@
+package main
+
+import "testing"
+
+// mapTagReader is a TagReader backed by a fixed map, for tests that don't
+// want to touch the filesystem.
+type mapTagReader map[Location]Tags
+
+func (m mapTagReader) ReadTags(path string) (Tags, error) {
+	return m[Location(path)], nil
+}
+
+func TestAvoidAdjacentArtistResolvesConflict(t *testing.T) {
+	locs := []Location{pathToLocation("/music/a1.mp3"), pathToLocation("/music/a2.mp3"), pathToLocation("/music/b1.mp3")}
+	reader := mapTagReader{
+		"/music/a1.mp3": Tags{Artist: "A"},
+		"/music/a2.mp3": Tags{Artist: "A"},
+		"/music/b1.mp3": Tags{Artist: "B"},
+	}
+
+	result := AvoidAdjacentArtist(locs, reader, 10)
+
+	if len(result) != len(locs) {
+		t.Fatalf("len(result) = %d, want %d", len(result), len(locs))
+	}
+	for i := 1; i < len(result); i++ {
+		if reader[Location(result[i].Text())].Artist == reader[Location(result[i-1].Text())].Artist {
+			t.Errorf("adjacent conflict remains at index %d: %v", i, result)
+		}
+	}
+}
+
+func TestAvoidAdjacentArtistLeavesUnsolvableConflictInPlace(t *testing.T) {
+	// Three tracks, all by the same artist: no swap can avoid an adjacency.
+	locs := []Location{pathToLocation("/music/a1.mp3"), pathToLocation("/music/a2.mp3"), pathToLocation("/music/a3.mp3")}
+	reader := mapTagReader{
+		"/music/a1.mp3": Tags{Artist: "A"},
+		"/music/a2.mp3": Tags{Artist: "A"},
+		"/music/a3.mp3": Tags{Artist: "A"},
+	}
+
+	result := AvoidAdjacentArtist(locs, reader, 10)
+
+	if len(result) != len(locs) {
+		t.Fatalf("len(result) = %d, want %d", len(result), len(locs))
+	}
+	seen := make(map[Location]bool, len(result))
+	for _, l := range result {
+		seen[l] = true
+	}
+	for _, l := range locs {
+		if !seen[l] {
+			t.Errorf("AvoidAdjacentArtist dropped %q", l)
+		}
+	}
+}
+
+func TestAvoidAdjacentArtistNoConflictIsNoop(t *testing.T) {
+	locs := []Location{pathToLocation("/music/a1.mp3"), pathToLocation("/music/b1.mp3"), pathToLocation("/music/c1.mp3")}
+	reader := mapTagReader{
+		"/music/a1.mp3": Tags{Artist: "A"},
+		"/music/b1.mp3": Tags{Artist: "B"},
+		"/music/c1.mp3": Tags{Artist: "C"},
+	}
+
+	result := AvoidAdjacentArtist(locs, reader, 10)
+
+	for i, l := range locs {
+		if result[i] != l {
+			t.Errorf("expected no reordering when there's no conflict, got %v", result)
+			break
+		}
+	}
+}
+
+func TestShuffleByTagsUnknownGroupBy(t *testing.T) {
+	_, err := ShuffleByTags(nil, mapTagReader{}, "bogus")
+	if err == nil {
+		t.Error("expected an error for an unknown groupBy")
+	}
+}
+
+func TestShuffleByAlbumKeepsTracksInOrder(t *testing.T) {
+	tagged := []taggedLocation{
+		{Loc: "a2", Tags: Tags{Artist: "X", Album: "Y", Track: 2}},
+		{Loc: "a1", Tags: Tags{Artist: "X", Album: "Y", Track: 1}},
+	}
+
+	result := shuffleByAlbum(tagged)
+
+	if len(result) != 2 || result[0] != "a1" || result[1] != "a2" {
+		t.Errorf("expected tracks reordered to track-number order within an album, got %v", result)
+	}
+}
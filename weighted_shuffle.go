@@ -0,0 +1,69 @@
+// Weighted "less-recently-played" shuffle, using rhythmdb.xml's per-track
+// play-count, last-played and rating to favor tracks that are higher-rated,
+// played less often, and not played recently.
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// trackWeight scores t for the weighted shuffle: higher rating, lower play
+// count, and longer time since last played all increase the weight.
+func trackWeight(t Track, found bool, now time.Time, halfLife, recencyTau, ratingBoost float64) float64 {
+	if !found {
+		return 1 // unknown to rhythmdb: treat as an average track
+	}
+
+	recencyDays := 3650.0 // never played: treat as long overdue
+	if t.LastPlayed > 0 {
+		recencyDays = now.Sub(time.Unix(t.LastPlayed, 0)).Hours() / 24
+	}
+
+	w := (float64(t.Rating)*ratingBoost + 1) *
+		math.Exp(-float64(t.PlayCount)/halfLife) *
+		(1 - math.Exp(-recencyDays/recencyTau))
+	if w <= 0 {
+		w = 1e-9
+	}
+	return w
+}
+
+// WeightedShuffle returns a permutation of locations drawn without
+// replacement according to trackWeight, via the A-Res weighted-reservoir
+// algorithm: each item draws u ~ U(0,1) and gets key = u^(1/w); sorting by
+// key descending yields a full weighted permutation in O(n log n).
+func WeightedShuffle(locations []Location, db *TrackDB, halfLife, recencyTau, ratingBoost float64) []Location {
+	byLocation := make(map[Location]Track, len(db.Tracks))
+	for _, t := range db.Tracks {
+		byLocation[t.Location] = t
+	}
+
+	type keyed struct {
+		loc Location
+		key float64
+	}
+	now := time.Now()
+	items := make([]keyed, len(locations))
+	for i, l := range locations {
+		t, found := byLocation[l]
+		w := trackWeight(t, found, now, halfLife, recencyTau, ratingBoost)
+
+		u := rnd.Float64()
+		if u <= 0 {
+			u = math.SmallestNonzeroFloat64
+		}
+		items[i] = keyed{loc: l, key: math.Pow(u, 1/w)}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].key > items[j].key
+	})
+
+	result := make([]Location, len(items))
+	for i, it := range items {
+		result[i] = it.loc
+	}
+	return result
+}
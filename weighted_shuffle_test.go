@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrackWeightUnknownTrack(t *testing.T) {
+	if got := trackWeight(Track{}, false, time.Now(), 100, 30, 1); got != 1 {
+		t.Errorf("trackWeight(not found) = %v, want 1", got)
+	}
+}
+
+func TestTrackWeightHigherRatingWeighsMore(t *testing.T) {
+	now := time.Now()
+	low := trackWeight(Track{Rating: 1}, true, now, 100, 30, 1)
+	high := trackWeight(Track{Rating: 5}, true, now, 100, 30, 1)
+	if high <= low {
+		t.Errorf("expected higher rating to weigh more: rating=1 -> %v, rating=5 -> %v", low, high)
+	}
+}
+
+func TestTrackWeightHigherPlayCountWeighsLess(t *testing.T) {
+	now := time.Now()
+	few := trackWeight(Track{PlayCount: 1}, true, now, 100, 30, 1)
+	many := trackWeight(Track{PlayCount: 50}, true, now, 100, 30, 1)
+	if many >= few {
+		t.Errorf("expected higher play count to weigh less: playCount=1 -> %v, playCount=50 -> %v", few, many)
+	}
+}
+
+func TestTrackWeightRecencyDecay(t *testing.T) {
+	now := time.Now()
+	recentlyPlayed := trackWeight(Track{LastPlayed: now.Add(-time.Hour).Unix()}, true, now, 100, 30, 1)
+	neverPlayed := trackWeight(Track{LastPlayed: 0}, true, now, 100, 30, 1)
+	longAgo := trackWeight(Track{LastPlayed: now.Add(-365 * 24 * time.Hour).Unix()}, true, now, 100, 30, 1)
+
+	if recentlyPlayed >= longAgo {
+		t.Errorf("expected a recently played track to weigh less than one played long ago: recent=%v, longAgo=%v", recentlyPlayed, longAgo)
+	}
+	if neverPlayed <= longAgo {
+		t.Errorf("expected a never-played track to weigh at least as much as one played long ago: never=%v, longAgo=%v", neverPlayed, longAgo)
+	}
+}
+
+func TestTrackWeightAlwaysPositive(t *testing.T) {
+	now := time.Now()
+	w := trackWeight(Track{Rating: 0, PlayCount: 1000000, LastPlayed: now.Unix()}, true, now, 100, 30, 1)
+	if w <= 0 {
+		t.Errorf("trackWeight = %v, want > 0", w)
+	}
+}
+
+func TestWeightedShufflePermutation(t *testing.T) {
+	locs := []Location{"a", "b", "c", "d", "e"}
+	db := &TrackDB{Tracks: []Track{
+		{Location: "a", Rating: 5, PlayCount: 0},
+		{Location: "b", Rating: 1, PlayCount: 100},
+		{Location: "c", Rating: 3, PlayCount: 10},
+		// d and e are absent from the DB, exercising the "unknown track" path.
+	}}
+
+	result := WeightedShuffle(locs, db, 100, 30, 1)
+
+	if len(result) != len(locs) {
+		t.Fatalf("len(result) = %d, want %d", len(result), len(locs))
+	}
+	seen := make(map[Location]bool, len(result))
+	for _, l := range result {
+		seen[l] = true
+	}
+	for _, l := range locs {
+		if !seen[l] {
+			t.Errorf("WeightedShuffle dropped %q", l)
+		}
+	}
+}